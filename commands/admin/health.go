@@ -0,0 +1,257 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/twmb/kafka-go/pkg/kmsg"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/out"
+)
+
+// healthPartition is the subset of a MetadataResponseTopicPartition that the
+// health commands below care about. The configured min.insync.replicas for
+// a partition's topic, when --min-isr is requested, is looked up separately
+// via describeMinISR rather than carried on this struct.
+type healthPartition struct {
+	Topic     string
+	Partition int32
+	Leader    int32
+	Replicas  []int32
+	ISR       []int32
+}
+
+// describeMinISR looks up the min.insync.replicas config for every topic
+// named, returning a topic -> configured value map. Every parsable value the
+// response carries is used, whether it is a topic override or a default;
+// topics with no parsable value (for example, topics that error) are simply
+// absent from the returned map.
+func describeMinISR(cl *client.Client, topics []string) map[string]int {
+	if len(topics) == 0 {
+		return nil
+	}
+	var req kmsg.DescribeConfigsRequest
+	for _, topic := range topics {
+		req.Resources = append(req.Resources, kmsg.DescribeConfigsRequestResource{
+			ResourceType: kmsg.ConfigResourceTypeTopic,
+			ResourceName: topic,
+			ConfigNames:  []string{"min.insync.replicas"},
+		})
+	}
+
+	kresp, err := cl.Client().Request(context.Background(), &req)
+	out.MaybeDie(err, "unable to describe configs for min.insync.replicas: %v", err)
+	resp := kresp.(*kmsg.DescribeConfigsResponse)
+
+	minISRs := make(map[string]int)
+	for _, resource := range resp.Resources {
+		for _, config := range resource.Configs {
+			if config.Value == nil {
+				continue
+			}
+			minISR, err := strconv.Atoi(*config.Value)
+			if err != nil {
+				continue
+			}
+			minISRs[resource.ResourceName] = minISR
+		}
+	}
+	return minISRs
+}
+
+// metadataPartitions requests metadata for the given topics (all topics if
+// none are given) and flattens the response into per-partition records.
+func metadataPartitions(cl *client.Client, topics []string) []healthPartition {
+	var req kmsg.MetadataRequest
+	for _, topic := range topics {
+		req.Topics = append(req.Topics, kmsg.MetadataRequestTopic{Topic: topic})
+	}
+
+	kresp, err := cl.Client().Request(context.Background(), &req)
+	out.MaybeDie(err, "unable to request metadata: %v", err)
+	resp := kresp.(*kmsg.MetadataResponse)
+
+	var partitions []healthPartition
+	for _, topic := range resp.Topics {
+		for _, partition := range topic.Partitions {
+			partitions = append(partitions, healthPartition{
+				Topic:     topic.Topic,
+				Partition: partition.Partition,
+				Leader:    partition.Leader,
+				Replicas:  partition.Replicas,
+				ISR:       partition.ISR,
+			})
+		}
+	}
+	return partitions
+}
+
+func healthUnderReplicatedCommand(cl *client.Client) *cobra.Command {
+	var minISR bool
+	var exitCode bool
+	cmd := &cobra.Command{
+		Use:   "under-replicated-partitions",
+		Short: "List partitions whose in sync replica set is behind their replica set.",
+		Long: `List partitions whose in sync replica set is behind their replica set.
+
+This issues a single Metadata request (for all topics if none are
+specified) and reports every partition where the in sync replica count is
+less than the replica count.
+
+With --min-isr, this additionally issues a DescribeConfigs request for the
+named topics (or all topics found) to fetch each topic's configured
+min.insync.replicas, and flags partitions whose in sync replica count is
+below that configured minimum, even if they are not otherwise
+under-replicated.
+
+With --exit-code, the command exits non-zero if any partitions are
+reported, so this can be wired into monitoring or CI health checks.
+`,
+
+		Example: `get under-replicated-partitions
+
+get under-replicated-partitions foo bar
+
+get under-replicated-partitions --min-isr --exit-code`,
+
+		Run: func(_ *cobra.Command, topics []string) {
+			partitions := metadataPartitions(cl, topics)
+
+			var minISRs map[string]int
+			if minISR {
+				queryTopics := topics
+				if len(queryTopics) == 0 {
+					seen := make(map[string]bool)
+					for _, partition := range partitions {
+						if !seen[partition.Topic] {
+							seen[partition.Topic] = true
+							queryTopics = append(queryTopics, partition.Topic)
+						}
+					}
+				}
+				minISRs = describeMinISR(cl, queryTopics)
+			}
+
+			type offender struct {
+				healthPartition
+				missing []int32
+			}
+			var offenders []offender
+			for _, partition := range partitions {
+				underReplicated := len(partition.ISR) < len(partition.Replicas)
+				belowMinISR := minISR && len(partition.ISR) < minISRs[partition.Topic]
+				if !underReplicated && !belowMinISR {
+					continue
+				}
+				isr := make(map[int32]bool, len(partition.ISR))
+				for _, id := range partition.ISR {
+					isr[id] = true
+				}
+				var missing []int32
+				for _, id := range partition.Replicas {
+					if !isr[id] {
+						missing = append(missing, id)
+					}
+				}
+				offenders = append(offenders, offender{partition, missing})
+			}
+
+			sort.Slice(offenders, func(i, j int) bool {
+				if offenders[i].Topic != offenders[j].Topic {
+					return offenders[i].Topic < offenders[j].Topic
+				}
+				return offenders[i].Partition < offenders[j].Partition
+			})
+
+			tw := out.BeginTabWrite()
+			defer tw.Flush()
+
+			fmt.Fprintf(tw, "TOPIC\tPARTITION\tLEADER\tREPLICAS\tISR\tMISSING FROM ISR\n")
+			for _, o := range offenders {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\n",
+					o.Topic,
+					o.Partition,
+					o.Leader,
+					o.Replicas,
+					o.ISR,
+					o.missing,
+				)
+			}
+
+			if exitCode && len(offenders) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&minISR, "min-isr", false, "also flag partitions below their topic's configured min.insync.replicas")
+	cmd.Flags().BoolVar(&exitCode, "exit-code", false, "exit non-zero if any partitions are reported")
+	return cmd
+}
+
+func healthOfflinePartitionsCommand(cl *client.Client) *cobra.Command {
+	var exitCode bool
+	cmd := &cobra.Command{
+		Use:   "offline-partitions",
+		Short: "List partitions with no leader.",
+		Long: `List partitions with no leader.
+
+This issues a single Metadata request (for all topics if none are
+specified) and reports every partition whose leader is -1, meaning the
+partition is currently unavailable for produces and consumes.
+
+With --exit-code, the command exits non-zero if any partitions are
+reported, so this can be wired into monitoring or CI health checks.
+`,
+
+		Example: `get offline-partitions
+
+get offline-partitions foo bar
+
+get offline-partitions --exit-code`,
+
+		Run: func(_ *cobra.Command, topics []string) {
+			partitions := metadataPartitions(cl, topics)
+
+			var offline []healthPartition
+			for _, partition := range partitions {
+				if partition.Leader == -1 {
+					offline = append(offline, partition)
+				}
+			}
+
+			sort.Slice(offline, func(i, j int) bool {
+				if offline[i].Topic != offline[j].Topic {
+					return offline[i].Topic < offline[j].Topic
+				}
+				return offline[i].Partition < offline[j].Partition
+			})
+
+			tw := out.BeginTabWrite()
+			defer tw.Flush()
+
+			fmt.Fprintf(tw, "TOPIC\tPARTITION\tLEADER\tREPLICAS\tISR\n")
+			for _, o := range offline {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\n",
+					o.Topic,
+					o.Partition,
+					o.Leader,
+					o.Replicas,
+					o.ISR,
+				)
+			}
+
+			if exitCode && len(offline) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&exitCode, "exit-code", false, "exit non-zero if any partitions are reported")
+	return cmd
+}
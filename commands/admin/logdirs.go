@@ -3,8 +3,10 @@ package admin
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 	"github.com/twmb/kafka-go/pkg/kerr"
@@ -15,8 +17,55 @@ import (
 	"github.com/twmb/kcl/out"
 )
 
+// LogdirsBrokerResult is one broker's response (or error) from a fanned out
+// DescribeLogDirs request. It is exported so other commands (e.g. the
+// metrics exporter) that need a cluster-wide log-dirs view can reuse
+// FetchLogDirs instead of re-implementing the fan-out.
+type LogdirsBrokerResult struct {
+	Broker int32
+	Resp   *kmsg.DescribeLogDirsResponse
+	Err    error
+}
+
+// FetchLogDirs issues req against every broker in brokers, or, if brokers is
+// empty, against every broker in the cluster as learned via a Metadata
+// request. Requests are sent in parallel; a broker that errors is reported
+// in its result without aborting the others.
+func FetchLogDirs(cl *client.Client, brokers []int32, req kmsg.DescribeLogDirsRequest) []LogdirsBrokerResult {
+	targets := brokers
+	if len(targets) == 0 {
+		kresp, err := cl.Client().Request(context.Background(), new(kmsg.MetadataRequest))
+		out.MaybeDie(err, "unable to request metadata to determine brokers: %v", err)
+		resp := kresp.(*kmsg.MetadataResponse)
+		for _, broker := range resp.Brokers {
+			targets = append(targets, broker.NodeID)
+		}
+	}
+
+	results := make([]LogdirsBrokerResult, len(targets))
+	var wg sync.WaitGroup
+	for i, broker := range targets {
+		wg.Add(1)
+		go func(i int, broker int32) {
+			defer wg.Done()
+			kresp, err := cl.Client().Broker(int(broker)).Request(context.Background(), &req)
+			if err != nil {
+				results[i] = LogdirsBrokerResult{Broker: broker, Err: err}
+				return
+			}
+			results[i] = LogdirsBrokerResult{Broker: broker, Resp: kresp.(*kmsg.DescribeLogDirsResponse)}
+		}(i, broker)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Broker < results[j].Broker })
+	return results
+}
+
 func logdirsDescribeCommand(cl *client.Client) *cobra.Command {
-	var broker int32
+	var brokers []int32
+	var summary bool
+	var top int
 	cmd := &cobra.Command{
 		Use:   "log-dirs",
 		Short: "Describe log directories for topic partitions.",
@@ -44,21 +93,26 @@ Input format is topic:1,2,3.
 Alternatively, if you just specify a topic, this will describe all partitions
 for that topic.
 
-By default, this command will return log dirs for the partition leaders.
+By default (with no --broker given), this command fans the request out to
+every broker in the cluster (learned via a Metadata request) in parallel and
+merges the results, so the output covers every replica rather than just
+partition leaders. A broker that errors is reported on stderr and skipped;
+it does not abort the rest of the command.
 
-If describing everything, this will merge all in sync replicas into the same
-response.
+You can restrict the brokers queried with the repeatable --broker argument.
 
-You can direct this request to specific brokers with the --broker argument,
-which allows you to control whether you are asking for information about
-replicas vs. the leader.
+With --summary, this prints aggregated disk usage per (broker, dir) and per
+topic, a cluster-wide total, and the --top largest partitions by size,
+instead of the per-partition table.
 `,
 
 		Example: `log-dirs foo:1,2,3 bar:3,4,5
 
 log-dirs foo
 
-log-dirs // describes all`,
+log-dirs // describes all, fanned out to every broker
+
+log-dirs --broker 1 --broker 2 --summary`,
 
 		Run: func(_ *cobra.Command, topics []string) {
 			var req kmsg.DescribeLogDirsRequest
@@ -98,57 +152,171 @@ log-dirs // describes all`,
 				}
 			}
 
-			var kresp kmsg.Response
-			var err error
-			if broker >= 0 {
-				kresp, err = cl.Client().Broker(int(broker)).Request(context.Background(), &req)
-			} else {
-				kresp, err = cl.Client().Request(context.Background(), &req)
+			results := FetchLogDirs(cl, brokers, req)
+
+			for _, r := range results {
+				if r.Err != nil {
+					fmt.Fprintf(os.Stderr, "broker %d: unable to describe log dirs: %v\n", r.Broker, r.Err)
+				}
 			}
-			out.MaybeDie(err, "unable to describe log dirs: %v", err)
+
 			if cl.AsJSON() {
-				out.ExitJSON(kresp)
+				merged := make(map[int32]*kmsg.DescribeLogDirsResponse, len(results))
+				for _, r := range results {
+					if r.Resp != nil {
+						merged[r.Broker] = r.Resp
+					}
+				}
+				out.ExitJSON(merged)
 			}
-			resp := kresp.(*kmsg.DescribeLogDirsResponse)
 
-			sort.Slice(resp.Dirs, func(i, j int) bool { return resp.Dirs[i].Dir < resp.Dirs[j].Dir })
-			for _, dir := range resp.Dirs {
-				sort.Slice(dir.Topics, func(i, j int) bool { return dir.Topics[i].Topic < dir.Topics[j].Topic })
-				for _, topic := range dir.Topics {
-					sort.Slice(topic.Partitions, func(i, j int) bool { return topic.Partitions[i].Partition < topic.Partitions[j].Partition })
+			for _, r := range results {
+				if r.Resp == nil {
+					continue
+				}
+				sort.Slice(r.Resp.Dirs, func(i, j int) bool { return r.Resp.Dirs[i].Dir < r.Resp.Dirs[j].Dir })
+				for _, dir := range r.Resp.Dirs {
+					sort.Slice(dir.Topics, func(i, j int) bool { return dir.Topics[i].Topic < dir.Topics[j].Topic })
+					for _, topic := range dir.Topics {
+						sort.Slice(topic.Partitions, func(i, j int) bool { return topic.Partitions[i].Partition < topic.Partitions[j].Partition })
+					}
 				}
 			}
 
+			if summary {
+				printLogdirsSummary(results, top)
+				return
+			}
+
 			tw := out.BeginTabWrite()
 			defer tw.Flush()
 
-			fmt.Fprintf(tw, "DIR\tDIR ERR\tTOPIC\tPARTITION\tSIZE\tOFFSET LAG\tIS FUTURE\n")
-			for _, dir := range resp.Dirs {
-				if err := kerr.ErrorForCode(dir.ErrorCode); err != nil {
-					fmt.Fprintf(tw, "%s\t%s\t\t\t\t\t\n", dir.Dir, err.Error())
+			fmt.Fprintf(tw, "BROKER\tDIR\tDIR ERR\tTOPIC\tPARTITION\tSIZE\tOFFSET LAG\tIS FUTURE\n")
+			for _, r := range results {
+				if r.Resp == nil {
 					continue
 				}
-				for _, topic := range dir.Topics {
-					for _, partition := range topic.Partitions {
-						fmt.Fprintf(tw, "%s\t\t%s\t%d\t%d\t%d\t%v\n",
-							dir.Dir,
-							topic.Topic,
-							partition.Partition,
-							partition.Size,
-							partition.OffsetLag,
-							partition.IsFuture,
-						)
+				for _, dir := range r.Resp.Dirs {
+					if err := kerr.ErrorForCode(dir.ErrorCode); err != nil {
+						fmt.Fprintf(tw, "%d\t%s\t%s\t\t\t\t\t\n", r.Broker, dir.Dir, err.Error())
+						continue
+					}
+					for _, topic := range dir.Topics {
+						for _, partition := range topic.Partitions {
+							fmt.Fprintf(tw, "%d\t%s\t\t%s\t%d\t%d\t%d\t%v\n",
+								r.Broker,
+								dir.Dir,
+								topic.Topic,
+								partition.Partition,
+								partition.Size,
+								partition.OffsetLag,
+								partition.IsFuture,
+							)
+						}
 					}
 				}
 			}
-
 		},
 	}
 
-	cmd.Flags().Int32VarP(&broker, "broker", "b", -1, "a specific broker to direct the request to")
+	cmd.Flags().Int32SliceVarP(&brokers, "broker", "b", nil, "specific broker(s) to direct the request to (repeatable); defaults to every broker in the cluster")
+	cmd.Flags().BoolVar(&summary, "summary", false, "print disk usage aggregated per broker/dir/topic instead of the per-partition table")
+	cmd.Flags().IntVar(&top, "top", 10, "number of largest partitions to show in --summary mode")
 	return cmd
 }
 
+// printLogdirsSummary prints per-(broker,dir) and per-topic byte totals, a
+// cluster-wide total, and the largest N partitions by size.
+func printLogdirsSummary(results []LogdirsBrokerResult, top int) {
+	type dirKey struct {
+		broker int32
+		dir    string
+	}
+	dirBytes := make(map[dirKey]int64)
+	topicBytes := make(map[string]int64)
+	var clusterBytes int64
+
+	type partitionSize struct {
+		broker    int32
+		dir       string
+		topic     string
+		partition int32
+		size      int64
+	}
+	var partitions []partitionSize
+
+	for _, r := range results {
+		if r.Resp == nil {
+			continue
+		}
+		for _, dir := range r.Resp.Dirs {
+			if kerr.ErrorForCode(dir.ErrorCode) != nil {
+				continue
+			}
+			for _, topic := range dir.Topics {
+				for _, partition := range topic.Partitions {
+					dirBytes[dirKey{r.Broker, dir.Dir}] += partition.Size
+					topicBytes[topic.Topic] += partition.Size
+					clusterBytes += partition.Size
+					partitions = append(partitions, partitionSize{
+						broker:    r.Broker,
+						dir:       dir.Dir,
+						topic:     topic.Topic,
+						partition: partition.Partition,
+						size:      partition.Size,
+					})
+				}
+			}
+		}
+	}
+
+	tw := out.BeginTabWrite()
+	fmt.Fprintf(tw, "BROKER\tDIR\tBYTES\n")
+	dirs := make([]dirKey, 0, len(dirBytes))
+	for k := range dirBytes {
+		dirs = append(dirs, k)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].broker != dirs[j].broker {
+			return dirs[i].broker < dirs[j].broker
+		}
+		return dirs[i].dir < dirs[j].dir
+	})
+	for _, k := range dirs {
+		fmt.Fprintf(tw, "%d\t%s\t%d\n", k.broker, k.dir, dirBytes[k])
+	}
+	tw.Flush()
+	fmt.Println()
+
+	tw = out.BeginTabWrite()
+	fmt.Fprintf(tw, "TOPIC\tBYTES\n")
+	topicNames := make([]string, 0, len(topicBytes))
+	for topic := range topicBytes {
+		topicNames = append(topicNames, topic)
+	}
+	sort.Strings(topicNames)
+	for _, topic := range topicNames {
+		fmt.Fprintf(tw, "%s\t%d\n", topic, topicBytes[topic])
+	}
+	tw.Flush()
+	fmt.Println()
+
+	fmt.Printf("CLUSTER TOTAL\t%d\n", clusterBytes)
+	fmt.Println()
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].size > partitions[j].size })
+	if top > 0 && len(partitions) > top {
+		partitions = partitions[:top]
+	}
+
+	tw = out.BeginTabWrite()
+	defer tw.Flush()
+	fmt.Fprintf(tw, "BROKER\tDIR\tTOPIC\tPARTITION\tBYTES\n")
+	for _, p := range partitions {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\n", p.broker, p.dir, p.topic, p.partition, p.size)
+	}
+}
+
 func logdirsAlterReplicasCommand(cl *client.Client) *cobra.Command {
 	var broker int32
 	cmd := &cobra.Command{
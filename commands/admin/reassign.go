@@ -0,0 +1,293 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/twmb/kafka-go/pkg/kerr"
+	"github.com/twmb/kafka-go/pkg/kmsg"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/flagutil"
+	"github.com/twmb/kcl/out"
+)
+
+// reassignPlanPartition mirrors the per-partition object in the JSON files
+// produced / consumed by kafka-reassign-partitions.sh, so that plans can be
+// ported between the two tools.
+type reassignPlanPartition struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Replicas  []int32 `json:"replicas"`
+}
+
+type reassignPlan struct {
+	Version    int                     `json:"version"`
+	Partitions []reassignPlanPartition `json:"partitions"`
+}
+
+func loadReassignPlan(file string) (map[string]map[int32][]int32, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read reassignment plan file: %w", err)
+	}
+	var plan reassignPlan
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return nil, fmt.Errorf("unable to parse reassignment plan file: %w", err)
+	}
+
+	assignments := make(map[string]map[int32][]int32)
+	for _, partition := range plan.Partitions {
+		if assignments[partition.Topic] == nil {
+			assignments[partition.Topic] = make(map[int32][]int32)
+		}
+		assignments[partition.Topic][partition.Partition] = partition.Replicas
+	}
+	return assignments, nil
+}
+
+// targetReplicas returns the replica set a partition will settle on once an
+// in-progress reassignment finishes: its current replicas minus whichever
+// ones are being removed.
+func targetReplicas(replicas, removing []int32) []int32 {
+	if len(removing) == 0 {
+		return replicas
+	}
+	removed := make(map[int32]bool, len(removing))
+	for _, broker := range removing {
+		removed[broker] = true
+	}
+	target := make([]int32, 0, len(replicas))
+	for _, broker := range replicas {
+		if !removed[broker] {
+			target = append(target, broker)
+		}
+	}
+	return target
+}
+
+func partitionReassignmentsAlterCommand(cl *client.Client) *cobra.Command {
+	var file string
+	var timeoutMillis int32
+	cmd := &cobra.Command{
+		Use:   "alter-partition-reassignments",
+		Short: "Alter the replica assignments of partitions (KIP-455).",
+		Long: `Alter the replica assignments of partitions (Kafka 2.4.0+).
+
+This command moves partitions between brokers by submitting a new replica
+set for each partition; the controller then drives the data copy in the
+background. See KIP-455 for the motivation and the semantics that replaced
+the old "reassign_partitions" Zookeeper znode.
+
+The input syntax is topic:partition=broker1,broker2,broker3.
+
+An empty replica list cancels any reassignment currently in progress for
+that partition, reverting to its original assignment:
+
+  alter-partition-reassignments foo:0=
+
+Alternatively, --file can point at a JSON plan in the same format that
+kafka-reassign-partitions.sh --generate produces, allowing plans generated
+by or for that script to be used here directly:
+
+  {
+    "version": 1,
+    "partitions": [
+      {"topic": "foo", "partition": 0, "replicas": [1, 2, 3]}
+    ]
+  }
+
+Partitions given on the command line are merged with (and take priority
+over) any partitions present in --file.
+`,
+
+		Example: `alter-partition-reassignments foo:0=1,2,3 foo:1=4,5,6
+
+alter-partition-reassignments foo:0= // cancels any reassignment for foo:0
+
+alter-partition-reassignments --file plan.json`,
+
+		Run: func(_ *cobra.Command, topics []string) {
+			assignments := make(map[string]map[int32][]int32)
+			if file != "" {
+				fromFile, err := loadReassignPlan(file)
+				out.MaybeDie(err, "%v", err)
+				assignments = fromFile
+			}
+
+			for _, topic := range topics {
+				parts := strings.SplitN(topic, "=", 2)
+				if len(parts) != 2 {
+					out.Die("improper format for topic:partition=replicas split (expected two strings after split, got %d)", len(parts))
+				}
+				tp := strings.SplitN(parts[0], ":", 2)
+				if len(tp) != 2 {
+					out.Die("improper format for topic:partition on %q", parts[0])
+				}
+				partition, err := strconv.ParseInt(tp[1], 10, 32)
+				out.MaybeDie(err, "unable to parse partition in %q: %v", parts[0], err)
+
+				var replicas []int32
+				if parts[1] != "" {
+					for _, replica := range strings.Split(parts[1], ",") {
+						broker, err := strconv.ParseInt(replica, 10, 32)
+						out.MaybeDie(err, "unable to parse replica broker in %q: %v", topic, err)
+						replicas = append(replicas, int32(broker))
+					}
+				}
+
+				if assignments[tp[0]] == nil {
+					assignments[tp[0]] = make(map[int32][]int32)
+				}
+				assignments[tp[0]][int32(partition)] = replicas
+			}
+
+			var req kmsg.AlterPartitionReassignmentsRequest
+			req.TimeoutMillis = timeoutMillis
+			for topic, partitions := range assignments {
+				reqTopic := kmsg.AlterPartitionReassignmentsRequestTopic{Topic: topic}
+				for partition, replicas := range partitions {
+					reqTopic.Partitions = append(reqTopic.Partitions, kmsg.AlterPartitionReassignmentsRequestTopicPartition{
+						Partition: partition,
+						Replicas:  replicas,
+					})
+				}
+				req.Topics = append(req.Topics, reqTopic)
+			}
+
+			kresp, err := cl.Client().Request(context.Background(), &req)
+			out.MaybeDie(err, "unable to alter partition reassignments: %v", err)
+			if cl.AsJSON() {
+				out.ExitJSON(kresp)
+			}
+			resp := kresp.(*kmsg.AlterPartitionReassignmentsResponse)
+			if err := kerr.ErrorForCode(resp.ErrorCode); err != nil {
+				out.Die("%v", err)
+			}
+
+			sort.Slice(resp.Topics, func(i, j int) bool { return resp.Topics[i].Topic < resp.Topics[j].Topic })
+			for _, topic := range resp.Topics {
+				sort.Slice(topic.Partitions, func(i, j int) bool { return topic.Partitions[i].Partition < topic.Partitions[j].Partition })
+			}
+
+			tw := out.BeginTabWrite()
+			defer tw.Flush()
+
+			fmt.Fprintf(tw, "TOPIC\tPARTITION\tERROR\n")
+			for _, topic := range resp.Topics {
+				for _, partition := range topic.Partitions {
+					msg := ""
+					if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+						msg = err.Error()
+					}
+					fmt.Fprintf(tw, "%s\t%d\t%s\n",
+						topic.Topic,
+						partition.Partition,
+						msg,
+					)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "path to a JSON reassignment plan (kafka-reassign-partitions.sh format)")
+	cmd.Flags().Int32VarP(&timeoutMillis, "timeout-ms", "t", 15000, "amount of time in milliseconds to wait for the request to complete")
+	return cmd
+}
+
+func partitionReassignmentsListCommand(cl *client.Client) *cobra.Command {
+	var timeoutMillis int32
+	cmd := &cobra.Command{
+		Use:   "list-partition-reassignments",
+		Short: "List in progress partition reassignments (KIP-455).",
+		Long: `List in progress partition reassignments (Kafka 2.4.0+).
+
+Input format is topic:1,2,3.
+
+Alternatively, if you just specify a topic, this will list all in progress
+reassignments for that topic.
+
+If no topics are specified, this lists all in progress reassignments
+cluster-wide.
+
+Adding replicas are replicas that are being added to the partition as part
+of the reassignment; removing replicas are replicas that will be dropped
+once the reassignment completes.
+`,
+
+		Example: `list-partition-reassignments foo:1,2,3 bar
+
+list-partition-reassignments // lists everything in progress`,
+
+		Run: func(_ *cobra.Command, topics []string) {
+			var req kmsg.ListPartitionReassignmentsRequest
+			req.TimeoutMillis = timeoutMillis
+			if topics != nil {
+				tps, err := flagutil.ParseTopicPartitions(topics)
+				out.MaybeDie(err, "improper topic partitions format on: %v", err)
+
+				// A topic given without partitions is listed in full, so
+				// its partitions are learned with a metadata request first.
+				var alls []string
+				for topic, partitions := range tps {
+					if len(partitions) == 0 {
+						alls = append(alls, topic)
+					}
+				}
+				if len(alls) > 0 {
+					for _, p := range metadataPartitions(cl, alls) {
+						tps[p.Topic] = append(tps[p.Topic], p.Partition)
+					}
+				}
+
+				for topic, partitions := range tps {
+					req.Topics = append(req.Topics, kmsg.ListPartitionReassignmentsRequestTopic{
+						Topic:      topic,
+						Partitions: partitions,
+					})
+				}
+			}
+
+			kresp, err := cl.Client().Request(context.Background(), &req)
+			out.MaybeDie(err, "unable to list partition reassignments: %v", err)
+			if cl.AsJSON() {
+				out.ExitJSON(kresp)
+			}
+			resp := kresp.(*kmsg.ListPartitionReassignmentsResponse)
+			if err := kerr.ErrorForCode(resp.ErrorCode); err != nil {
+				out.Die("%v", err)
+			}
+
+			sort.Slice(resp.Topics, func(i, j int) bool { return resp.Topics[i].Topic < resp.Topics[j].Topic })
+			for _, topic := range resp.Topics {
+				sort.Slice(topic.Partitions, func(i, j int) bool { return topic.Partitions[i].Partition < topic.Partitions[j].Partition })
+			}
+
+			tw := out.BeginTabWrite()
+			defer tw.Flush()
+
+			fmt.Fprintf(tw, "TOPIC\tPARTITION\tREPLICAS\tADDING\tREMOVING\tTARGET\n")
+			for _, topic := range resp.Topics {
+				for _, partition := range topic.Partitions {
+					fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\n",
+						topic.Topic,
+						partition.Partition,
+						partition.Replicas,
+						partition.AddingReplicas,
+						partition.RemovingReplicas,
+						targetReplicas(partition.Replicas, partition.RemovingReplicas),
+					)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().Int32VarP(&timeoutMillis, "timeout-ms", "t", 15000, "amount of time in milliseconds to wait for the request to complete")
+	return cmd
+}
@@ -0,0 +1,449 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/twmb/kafka-go/pkg/kerr"
+	"github.com/twmb/kafka-go/pkg/kmsg"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/out"
+)
+
+// rebalancePartition is one partition as the planner sees it: its current
+// replica set and the size on disk (from DescribeLogDirs) of the replica the
+// plan is tracking. The broker hosting that replica is kept alongside it in
+// trackedPartition.
+type rebalancePartition struct {
+	Topic     string
+	Partition int32
+	Replicas  []int32
+	Bytes     int64
+}
+
+// rebalanceMove is one planned reassignment: move topic/partition's replica
+// off FromBroker and onto ToBroker, leaving every other replica untouched.
+type rebalanceMove struct {
+	Topic      string `json:"topic"`
+	Partition  int32  `json:"partition"`
+	FromBroker int32  `json:"from_broker"`
+	ToBroker   int32  `json:"to_broker"`
+	Bytes      int64  `json:"bytes"`
+	// Replicas is the partition's full replica list as of this move,
+	// i.e. after this move and every move before it in the plan has been
+	// applied. Execution submits this directly rather than re-deriving it,
+	// so that two moves for the same partition landing in the same
+	// --max-in-flight batch still chain correctly instead of clobbering
+	// each other.
+	Replicas []int32 `json:"replicas"`
+}
+
+// rebalancePlan is the full output of the planner: the ordered moves to
+// make and the byte distribution across brokers predicted once every move
+// has completed.
+type rebalancePlan struct {
+	Moves                []rebalanceMove  `json:"moves"`
+	CurrentBrokerBytes   map[string]int64 `json:"current_broker_bytes"`
+	PredictedBrokerBytes map[string]int64 `json:"predicted_broker_bytes"`
+}
+
+func rebalanceCommand(cl *client.Client) *cobra.Command {
+	var tolerance float64
+	var maxInFlight int
+	var execute bool
+	cmd := &cobra.Command{
+		Use:   "rebalance",
+		Short: "Plan and execute partition moves to even out disk usage across brokers.",
+		Long: `Plan and execute partition moves to even out disk usage across brokers.
+
+This builds a broker -> bytes map (summed over all of a broker's log dirs;
+per-dir usage is not tracked) and a partition -> size map from a
+cluster-wide DescribeLogDirs fan-out (the same one "log-dirs --summary"
+uses), then greedily reassigns one replica at a time from the most-loaded
+broker to the least-loaded broker that does not already host that
+partition, preferring a destination whose rack (from Metadata) is not
+already represented among the partition's other replicas. Moves continue
+until the most-loaded broker is at most --tolerance percent above the
+mean, or no movable partition or destination is left. A move never takes
+its source broker below --tolerance percent under the mean, but a broker
+that is already that far under the mean does not by itself cause more
+moves.
+
+By default this only prints the plan (as JSON) along with the predicted
+post-move byte distribution per broker; it does not change anything. Pass
+--execute to submit the plan via AlterPartitionReassignments, in batches of
+--max-in-flight concurrent reassignments, polling
+ListPartitionReassignments until each batch completes before starting the
+next.
+`,
+
+		Example: `rebalance
+
+rebalance --tolerance 5 --max-in-flight 10
+
+rebalance --execute`,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			plan := buildRebalancePlan(cl, tolerance)
+
+			raw, err := json.MarshalIndent(plan, "", "  ")
+			out.MaybeDie(err, "unable to marshal rebalance plan: %v", err)
+			fmt.Println(string(raw))
+
+			if !execute {
+				return
+			}
+			if len(plan.Moves) == 0 {
+				return
+			}
+			executeRebalancePlan(cl, plan, maxInFlight)
+		},
+	}
+
+	cmd.Flags().Float64Var(&tolerance, "tolerance", 10, "allowed percent deviation from the mean bytes-per-broker before a broker is considered unbalanced")
+	cmd.Flags().IntVar(&maxInFlight, "max-in-flight", 5, "maximum number of concurrent reassignments to have in flight at once")
+	cmd.Flags().BoolVar(&execute, "execute", false, "submit the plan instead of only printing it")
+	return cmd
+}
+
+// buildRebalancePlan fans DescribeLogDirs and Metadata out across the
+// cluster and greedily plans replica moves to even out disk usage.
+func buildRebalancePlan(cl *client.Client, tolerancePct float64) rebalancePlan {
+	meta := metadataForRebalance(cl)
+	partitions, brokerBytes := rebalancePartitionsFromLogDirs(cl, meta.replicas)
+
+	for broker := range meta.racks {
+		if _, ok := brokerBytes[broker]; !ok {
+			brokerBytes[broker] = 0
+		}
+	}
+
+	var mean float64
+	if len(brokerBytes) > 0 {
+		var total int64
+		for _, b := range brokerBytes {
+			total += b
+		}
+		mean = float64(total) / float64(len(brokerBytes))
+	}
+	tolerance := mean * tolerancePct / 100
+
+	currentBrokerBytes := make(map[string]int64, len(brokerBytes))
+	for broker, bytes := range brokerBytes {
+		currentBrokerBytes[fmt.Sprintf("%d", broker)] = bytes
+	}
+
+	var moves []rebalanceMove
+	const maxIterations = 10000
+	for i := 0; i < maxIterations; i++ {
+		from, fromBytes := mostLoadedBroker(brokerBytes)
+		if fromBytes <= int64(mean+tolerance) {
+			break
+		}
+
+		partition, ok := largestMovablePartition(partitions, from, brokerBytes, mean-tolerance)
+		if !ok {
+			break
+		}
+
+		to, ok := bestDestination(partition, from, brokerBytes, meta.racks)
+		if !ok {
+			break
+		}
+
+		brokerBytes[from] -= partition.Bytes
+		brokerBytes[to] += partition.Bytes
+		partition.replaceReplica(from, to)
+
+		moves = append(moves, rebalanceMove{
+			Topic:      partition.Topic,
+			Partition:  partition.Partition,
+			FromBroker: from,
+			ToBroker:   to,
+			Bytes:      partition.Bytes,
+			Replicas:   append([]int32(nil), partition.Replicas...),
+		})
+	}
+
+	predictedBrokerBytes := make(map[string]int64, len(brokerBytes))
+	for broker, bytes := range brokerBytes {
+		predictedBrokerBytes[fmt.Sprintf("%d", broker)] = bytes
+	}
+
+	return rebalancePlan{
+		Moves:                moves,
+		CurrentBrokerBytes:   currentBrokerBytes,
+		PredictedBrokerBytes: predictedBrokerBytes,
+	}
+}
+
+// rebalanceMetadata is the broker rack map and the current replica set per
+// partition, both sourced from a single Metadata request.
+type rebalanceMetadata struct {
+	racks    map[int32]string
+	replicas map[string]map[int32][]int32 // topic -> partition -> replicas
+}
+
+func metadataForRebalance(cl *client.Client) rebalanceMetadata {
+	kresp, err := cl.Client().Request(context.Background(), new(kmsg.MetadataRequest))
+	out.MaybeDie(err, "unable to request metadata: %v", err)
+	resp := kresp.(*kmsg.MetadataResponse)
+
+	meta := rebalanceMetadata{
+		racks:    make(map[int32]string, len(resp.Brokers)),
+		replicas: make(map[string]map[int32][]int32, len(resp.Topics)),
+	}
+	for _, broker := range resp.Brokers {
+		rack := ""
+		if broker.Rack != nil {
+			rack = *broker.Rack
+		}
+		meta.racks[broker.NodeID] = rack
+	}
+	for _, topic := range resp.Topics {
+		meta.replicas[topic.Topic] = make(map[int32][]int32, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			meta.replicas[topic.Topic][partition.Partition] = partition.Replicas
+		}
+	}
+	return meta
+}
+
+// trackedPartition is a rebalancePartition together with the broker that
+// the DescribeLogDirs entry used for Bytes came from, and a mutable replica
+// list that the planner updates in place as it plans moves.
+type trackedPartition struct {
+	rebalancePartition
+	broker int32
+}
+
+func (p *trackedPartition) trackedBroker() int32 { return p.broker }
+
+func (p *trackedPartition) replaceReplica(from, to int32) {
+	for i, broker := range p.Replicas {
+		if broker == from {
+			p.Replicas[i] = to
+			p.broker = to
+			return
+		}
+	}
+}
+
+// rebalancePartitionsFromLogDirs merges a cluster-wide DescribeLogDirs
+// fan-out with each partition's replica set from metadata, picking the
+// largest replica reported for each partition as the one the plan tracks
+// (that's the one whose disk usage the planner is trying to move), and
+// separately sums every (broker, topic, partition) triple each broker
+// actually reported into a per-broker byte total. The two are deliberately
+// not derived from each other: with RF>1 the same topic/partition shows up
+// once per replica-hosting broker, each with its own real size, so only a
+// dedicated sum over every reported triple reflects what DescribeLogDirs
+// actually said each broker is holding.
+func rebalancePartitionsFromLogDirs(cl *client.Client, replicas map[string]map[int32][]int32) ([]*trackedPartition, map[int32]int64) {
+	results := FetchLogDirs(cl, nil, kmsg.DescribeLogDirsRequest{})
+
+	byPartition := make(map[string]*trackedPartition)
+	brokerBytes := make(map[int32]int64, len(results))
+	for _, r := range results {
+		if r.Resp == nil {
+			continue
+		}
+		if _, ok := brokerBytes[r.Broker]; !ok {
+			brokerBytes[r.Broker] = 0
+		}
+		for _, dir := range r.Resp.Dirs {
+			if kerr.ErrorForCode(dir.ErrorCode) != nil {
+				continue
+			}
+			for _, topic := range dir.Topics {
+				for _, partition := range topic.Partitions {
+					brokerBytes[r.Broker] += partition.Size
+
+					key := fmt.Sprintf("%s/%d", topic.Topic, partition.Partition)
+					existing, ok := byPartition[key]
+					if ok && existing.Bytes >= partition.Size {
+						continue
+					}
+					byPartition[key] = &trackedPartition{
+						rebalancePartition: rebalancePartition{
+							Topic:     topic.Topic,
+							Partition: partition.Partition,
+							Replicas:  append([]int32(nil), replicas[topic.Topic][partition.Partition]...),
+							Bytes:     partition.Size,
+						},
+						broker: r.Broker,
+					}
+				}
+			}
+		}
+	}
+
+	partitions := make([]*trackedPartition, 0, len(byPartition))
+	for _, p := range byPartition {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+	return partitions, brokerBytes
+}
+
+func mostLoadedBroker(brokerBytes map[int32]int64) (int32, int64) {
+	var best int32
+	var bestBytes int64 = -1
+	for broker, bytes := range brokerBytes {
+		if bytes > bestBytes {
+			best, bestBytes = broker, bytes
+		}
+	}
+	return best, bestBytes
+}
+
+// largestMovablePartition returns the largest partition hosted on from
+// whose removal wouldn't take from below the floor, i.e. a partition worth
+// moving off the most-loaded broker.
+func largestMovablePartition(partitions []*trackedPartition, from int32, brokerBytes map[int32]int64, floor float64) (*trackedPartition, bool) {
+	var best *trackedPartition
+	for _, p := range partitions {
+		if p.trackedBroker() != from {
+			continue
+		}
+		// Don't plan a move that would push the source broker below the
+		// floor; that just trades which broker is unbalanced.
+		if float64(brokerBytes[from]-p.Bytes) < floor {
+			continue
+		}
+		if best == nil || p.Bytes > best.Bytes {
+			best = p
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	return best, true
+}
+
+// bestDestination picks the least-loaded broker that doesn't already host
+// partition, preferring one whose rack isn't already represented among the
+// partition's other replicas.
+func bestDestination(partition *trackedPartition, from int32, brokerBytes map[int32]int64, racks map[int32]string) (int32, bool) {
+	hosts := make(map[int32]bool, len(partition.Replicas))
+	otherRacks := make(map[string]bool, len(partition.Replicas))
+	for _, broker := range partition.Replicas {
+		hosts[broker] = true
+		if broker != from {
+			otherRacks[racks[broker]] = true
+		}
+	}
+
+	var candidates []int32
+	for broker := range brokerBytes {
+		if hosts[broker] {
+			continue
+		}
+		candidates = append(candidates, broker)
+	}
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	sort.Slice(candidates, func(i, j int) bool { return brokerBytes[candidates[i]] < brokerBytes[candidates[j]] })
+
+	for _, broker := range candidates {
+		if !otherRacks[racks[broker]] {
+			return broker, true
+		}
+	}
+	return candidates[0], true
+}
+
+// executeRebalancePlan submits plan.Moves via AlterPartitionReassignments
+// in batches of at most maxInFlight, waiting for each batch to finish (as
+// observed through ListPartitionReassignments) before starting the next.
+func executeRebalancePlan(cl *client.Client, plan rebalancePlan, maxInFlight int) {
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+
+	for start := 0; start < len(plan.Moves); start += maxInFlight {
+		end := start + maxInFlight
+		if end > len(plan.Moves) {
+			end = len(plan.Moves)
+		}
+		batch := plan.Moves[start:end]
+
+		// A partition can legitimately appear more than once in a batch
+		// (the planner can move it off a broker and then, a few
+		// iterations later, move it again once its new home becomes
+		// overloaded). Each move's Replicas already reflects every move
+		// before it in the plan, so the last occurrence in the batch
+		// carries the correct, fully-chained replica list; just let it
+		// win rather than recomputing from the live (pre-plan) cluster
+		// state.
+		replicas := make(map[string]map[int32][]int32)
+		for _, move := range batch {
+			if replicas[move.Topic] == nil {
+				replicas[move.Topic] = make(map[int32][]int32)
+			}
+			replicas[move.Topic][move.Partition] = move.Replicas
+		}
+
+		var req kmsg.AlterPartitionReassignmentsRequest
+		for topic, partitions := range replicas {
+			reqTopic := kmsg.AlterPartitionReassignmentsRequestTopic{Topic: topic}
+			for partition, newReplicas := range partitions {
+				reqTopic.Partitions = append(reqTopic.Partitions, kmsg.AlterPartitionReassignmentsRequestTopicPartition{
+					Partition: partition,
+					Replicas:  newReplicas,
+				})
+			}
+			req.Topics = append(req.Topics, reqTopic)
+		}
+
+		kresp, err := cl.Client().Request(context.Background(), &req)
+		out.MaybeDie(err, "unable to submit reassignment batch: %v", err)
+		resp := kresp.(*kmsg.AlterPartitionReassignmentsResponse)
+		out.MaybeDie(kerr.ErrorForCode(resp.ErrorCode), "unable to submit reassignment batch: %v", kerr.ErrorForCode(resp.ErrorCode))
+
+		waitForReassignments(cl, batch)
+	}
+}
+
+// waitForReassignments polls ListPartitionReassignments until none of the
+// partitions in batch are still in progress.
+func waitForReassignments(cl *client.Client, batch []rebalanceMove) {
+	for {
+		var req kmsg.ListPartitionReassignmentsRequest
+		byTopic := make(map[string][]int32)
+		for _, move := range batch {
+			byTopic[move.Topic] = append(byTopic[move.Topic], move.Partition)
+		}
+		for topic, partitions := range byTopic {
+			req.Topics = append(req.Topics, kmsg.ListPartitionReassignmentsRequestTopic{
+				Topic:      topic,
+				Partitions: partitions,
+			})
+		}
+
+		kresp, err := cl.Client().Request(context.Background(), &req)
+		out.MaybeDie(err, "unable to list partition reassignments: %v", err)
+		resp := kresp.(*kmsg.ListPartitionReassignmentsResponse)
+		out.MaybeDie(kerr.ErrorForCode(resp.ErrorCode), "unable to list partition reassignments: %v", kerr.ErrorForCode(resp.ErrorCode))
+
+		inProgress := 0
+		for _, topic := range resp.Topics {
+			inProgress += len(topic.Partitions)
+		}
+		if inProgress == 0 {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
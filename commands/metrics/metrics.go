@@ -0,0 +1,480 @@
+// Package metrics implements the `kcl metrics` subcommand, a small
+// Prometheus exporter that scrapes the connected cluster on an interval and
+// serves the results on /metrics, so kcl can stand in for a dedicated
+// kafka_exporter deployment.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+	"github.com/twmb/kafka-go/pkg/kerr"
+	"github.com/twmb/kafka-go/pkg/kmsg"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/commands/admin"
+	"github.com/twmb/kcl/out"
+)
+
+// Command returns the `kcl metrics` command.
+func Command(cl *client.Client) *cobra.Command {
+	var interval time.Duration
+	var listenAddr string
+	var topicRegex string
+	var groupRegex string
+
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Expose a Prometheus /metrics endpoint scraping the connected cluster.",
+		Long: `Expose a Prometheus /metrics endpoint scraping the connected cluster.
+
+On --interval, this requests cluster metadata, per-broker log dirs, and
+consumer group offsets, and republishes them as gauges: broker count,
+per-topic partition count, per-partition leader/replicas/ISR/current and
+oldest offsets, an under-replicated flag, per-(broker,dir) log-dir bytes,
+per-partition offset lag, and per-(group,topic,partition) consumer lag.
+
+--topic-regex and --group-regex restrict which topics and consumer groups
+are scraped, which matters on large clusters where scraping everything on
+a tight interval is expensive.
+
+This is meant as a lightweight stand-in for kafka_exporter when deploying
+a separate exporter binary isn't worth it.
+`,
+
+		Example: `metrics
+
+metrics --listen-addr :9308 --interval 30s
+
+metrics --topic-regex '^orders-.*' --group-regex '^payments-.*'`,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			var topicRe, groupRe *regexp.Regexp
+			var err error
+			if topicRegex != "" {
+				topicRe, err = regexp.Compile(topicRegex)
+				out.MaybeDie(err, "invalid --topic-regex: %v", err)
+			}
+			if groupRegex != "" {
+				groupRe, err = regexp.Compile(groupRegex)
+				out.MaybeDie(err, "invalid --group-regex: %v", err)
+			}
+
+			c := newCollector(cl, topicRe, groupRe)
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(c.collectors()...)
+
+			go c.loop(interval)
+
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+			err = http.ListenAndServe(listenAddr, mux)
+			out.MaybeDie(err, "metrics server exited: %v", err)
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 15*time.Second, "scrape interval")
+	cmd.Flags().StringVar(&listenAddr, "listen-addr", ":9308", "address to listen on for /metrics")
+	cmd.Flags().StringVar(&topicRegex, "topic-regex", "", "only scrape topics matching this regex")
+	cmd.Flags().StringVar(&groupRegex, "group-regex", "", "only scrape consumer groups matching this regex")
+	return cmd
+}
+
+// collector holds every gauge the exporter publishes and the cluster
+// filters controlling what gets scraped.
+type collector struct {
+	cl      *client.Client
+	topicRe *regexp.Regexp
+	groupRe *regexp.Regexp
+
+	brokerCount              prometheus.Gauge
+	topicPartitionCount      *prometheus.GaugeVec
+	partitionLeader          *prometheus.GaugeVec
+	partitionReplicas        *prometheus.GaugeVec
+	partitionISR             *prometheus.GaugeVec
+	partitionUnderReplicated *prometheus.GaugeVec
+	partitionCurrentOffset   *prometheus.GaugeVec
+	partitionOldestOffset    *prometheus.GaugeVec
+	logdirBytes              *prometheus.GaugeVec
+	partitionOffsetLag       *prometheus.GaugeVec
+	groupLag                 *prometheus.GaugeVec
+}
+
+func newCollector(cl *client.Client, topicRe, groupRe *regexp.Regexp) *collector {
+	return &collector{
+		cl:      cl,
+		topicRe: topicRe,
+		groupRe: groupRe,
+
+		brokerCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kcl_broker_count",
+			Help: "Number of brokers in the cluster.",
+		}),
+		topicPartitionCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_topic_partition_count",
+			Help: "Number of partitions in a topic.",
+		}, []string{"topic"}),
+		partitionLeader: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_leader",
+			Help: "Broker ID of a partition's leader.",
+		}, []string{"topic", "partition"}),
+		partitionReplicas: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_replicas",
+			Help: "Number of replicas a partition is assigned.",
+		}, []string{"topic", "partition"}),
+		partitionISR: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_isr",
+			Help: "Number of in sync replicas a partition currently has.",
+		}, []string{"topic", "partition"}),
+		partitionUnderReplicated: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_under_replicated",
+			Help: "1 if a partition's ISR count is less than its replica count, else 0.",
+		}, []string{"topic", "partition"}),
+		partitionCurrentOffset: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_current_offset",
+			Help: "A partition's current (log end) offset.",
+		}, []string{"topic", "partition"}),
+		partitionOldestOffset: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_oldest_offset",
+			Help: "A partition's oldest retained offset.",
+		}, []string{"topic", "partition"}),
+		logdirBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_logdir_bytes",
+			Help: "Bytes used by a broker's log directory, counting only topics matching --topic-regex if set.",
+		}, []string{"broker", "dir"}),
+		partitionOffsetLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_partition_offset_lag",
+			Help: "A replica's offset lag from DescribeLogDirs: how far its log end offset is behind the high watermark, or, for a future dir, behind the current replica's log end offset.",
+		}, []string{"broker", "dir", "topic", "partition"}),
+		groupLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kcl_group_lag",
+			Help: "A consumer group's lag on a topic partition.",
+		}, []string{"group", "topic", "partition"}),
+	}
+}
+
+func (c *collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.brokerCount,
+		c.topicPartitionCount,
+		c.partitionLeader,
+		c.partitionReplicas,
+		c.partitionISR,
+		c.partitionUnderReplicated,
+		c.partitionCurrentOffset,
+		c.partitionOldestOffset,
+		c.logdirBytes,
+		c.partitionOffsetLag,
+		c.groupLag,
+	}
+}
+
+// loop scrapes on every tick until the process exits. Scrape errors are
+// logged to stderr and do not stop the loop, so a transient broker outage
+// doesn't take the exporter itself down.
+func (c *collector) loop(interval time.Duration) {
+	for {
+		if err := c.scrape(); err != nil {
+			fmt.Fprintf(os.Stderr, "scrape failed: %v\n", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (c *collector) scrape() error {
+	topics, err := c.scrapeMetadata()
+	if err != nil {
+		return fmt.Errorf("unable to scrape metadata: %w", err)
+	}
+
+	currentOffsets, err := c.scrapeOffsets(topics)
+	if err != nil {
+		return fmt.Errorf("unable to scrape offsets: %w", err)
+	}
+
+	if err := c.scrapeLogDirs(); err != nil {
+		return fmt.Errorf("unable to scrape log dirs: %w", err)
+	}
+
+	if err := c.scrapeGroupLag(topics, currentOffsets); err != nil {
+		return fmt.Errorf("unable to scrape group lag: %w", err)
+	}
+
+	return nil
+}
+
+// scrapeMetadata publishes broker/partition level gauges and returns the
+// topic -> partitions map used by the offset and group lag scrapes.
+func (c *collector) scrapeMetadata() (map[string][]int32, error) {
+	kresp, err := c.cl.Client().Request(context.Background(), new(kmsg.MetadataRequest))
+	if err != nil {
+		return nil, err
+	}
+	resp := kresp.(*kmsg.MetadataResponse)
+
+	c.brokerCount.Set(float64(len(resp.Brokers)))
+
+	c.topicPartitionCount.Reset()
+	c.partitionLeader.Reset()
+	c.partitionReplicas.Reset()
+	c.partitionISR.Reset()
+	c.partitionUnderReplicated.Reset()
+
+	topics := make(map[string][]int32)
+	for _, topic := range resp.Topics {
+		if c.topicRe != nil && !c.topicRe.MatchString(topic.Topic) {
+			continue
+		}
+		c.topicPartitionCount.WithLabelValues(topic.Topic).Set(float64(len(topic.Partitions)))
+		for _, partition := range topic.Partitions {
+			topics[topic.Topic] = append(topics[topic.Topic], partition.Partition)
+
+			partitionLabel := fmt.Sprintf("%d", partition.Partition)
+			c.partitionLeader.WithLabelValues(topic.Topic, partitionLabel).Set(float64(partition.Leader))
+			c.partitionReplicas.WithLabelValues(topic.Topic, partitionLabel).Set(float64(len(partition.Replicas)))
+			c.partitionISR.WithLabelValues(topic.Topic, partitionLabel).Set(float64(len(partition.ISR)))
+			underReplicated := 0.0
+			if len(partition.ISR) < len(partition.Replicas) {
+				underReplicated = 1.0
+			}
+			c.partitionUnderReplicated.WithLabelValues(topic.Topic, partitionLabel).Set(underReplicated)
+		}
+	}
+	return topics, nil
+}
+
+// scrapeOffsets publishes current and oldest offset gauges for every
+// partition in topics, and returns the current offsets for group lag math.
+func (c *collector) scrapeOffsets(topics map[string][]int32) (map[string]map[int32]int64, error) {
+	current, err := c.listOffsets(topics, -1)
+	if err != nil {
+		return nil, err
+	}
+	oldest, err := c.listOffsets(topics, -2)
+	if err != nil {
+		return nil, err
+	}
+
+	c.partitionCurrentOffset.Reset()
+	c.partitionOldestOffset.Reset()
+	for topic, partitions := range current {
+		for partition, offset := range partitions {
+			c.partitionCurrentOffset.WithLabelValues(topic, fmt.Sprintf("%d", partition)).Set(float64(offset))
+		}
+	}
+	for topic, partitions := range oldest {
+		for partition, offset := range partitions {
+			c.partitionOldestOffset.WithLabelValues(topic, fmt.Sprintf("%d", partition)).Set(float64(offset))
+		}
+	}
+	return current, nil
+}
+
+// listOffsets issues a ListOffsets request for every partition in topics at
+// the given timestamp (-1 for latest, -2 for earliest) and returns a
+// topic -> partition -> offset map.
+func (c *collector) listOffsets(topics map[string][]int32, timestamp int64) (map[string]map[int32]int64, error) {
+	var req kmsg.ListOffsetsRequest
+	req.ReplicaID = -1
+	for topic, partitions := range topics {
+		reqTopic := kmsg.ListOffsetsRequestTopic{Topic: topic}
+		for _, partition := range partitions {
+			reqTopic.Partitions = append(reqTopic.Partitions, kmsg.ListOffsetsRequestTopicPartition{
+				Partition: partition,
+				Timestamp: timestamp,
+			})
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	kresp, err := c.cl.Client().Request(context.Background(), &req)
+	if err != nil {
+		return nil, err
+	}
+	resp := kresp.(*kmsg.ListOffsetsResponse)
+
+	offsets := make(map[string]map[int32]int64)
+	for _, topic := range resp.Topics {
+		offsets[topic.Topic] = make(map[int32]int64)
+		for _, partition := range topic.Partitions {
+			if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+				fmt.Fprintf(os.Stderr, "list offsets %s-%d: %v\n", topic.Topic, partition.Partition, err)
+				continue
+			}
+			offsets[topic.Topic][partition.Partition] = partition.Offset
+		}
+	}
+	return offsets, nil
+}
+
+// scrapeLogDirs fans DescribeLogDirs out to every broker via the same
+// helper logdirsDescribeCommand uses, and publishes per-(broker,dir) byte
+// totals and per-partition offset lag.
+func (c *collector) scrapeLogDirs() error {
+	// FetchLogDirs dies if its own Metadata request fails when given no
+	// brokers, so the brokers are learned here and errors returned instead.
+	kresp, err := c.cl.Client().Request(context.Background(), new(kmsg.MetadataRequest))
+	if err != nil {
+		return err
+	}
+	var brokers []int32
+	for _, broker := range kresp.(*kmsg.MetadataResponse).Brokers {
+		brokers = append(brokers, broker.NodeID)
+	}
+	if len(brokers) == 0 {
+		return fmt.Errorf("metadata response listed no brokers")
+	}
+	results := admin.FetchLogDirs(c.cl, brokers, kmsg.DescribeLogDirsRequest{})
+
+	c.logdirBytes.Reset()
+	c.partitionOffsetLag.Reset()
+
+	for _, r := range results {
+		if r.Resp == nil {
+			continue
+		}
+		broker := fmt.Sprintf("%d", r.Broker)
+		for _, dir := range r.Resp.Dirs {
+			if err := kerr.ErrorForCode(dir.ErrorCode); err != nil {
+				fmt.Fprintf(os.Stderr, "broker %s dir %s: %v\n", broker, dir.Dir, err)
+				continue
+			}
+			var dirBytes int64
+			for _, topic := range dir.Topics {
+				if c.topicRe != nil && !c.topicRe.MatchString(topic.Topic) {
+					continue
+				}
+				for _, partition := range topic.Partitions {
+					dirBytes += partition.Size
+					c.partitionOffsetLag.WithLabelValues(
+						broker, dir.Dir, topic.Topic, fmt.Sprintf("%d", partition.Partition),
+					).Set(float64(partition.OffsetLag))
+				}
+			}
+			c.logdirBytes.WithLabelValues(broker, dir.Dir).Set(float64(dirBytes))
+		}
+	}
+	return nil
+}
+
+// scrapeGroupLag publishes per-(group,topic,partition) consumer lag, using
+// the current log end offsets already scraped for the current offset side
+// of the lag math.
+//
+// This follows ListGroups -> DescribeGroups -> OffsetFetch -> ListOffsets:
+// DescribeGroups gives each member's assignment (which this decodes via the
+// standard consumer embedded protocol) so that OffsetFetch can be issued
+// with an explicit topic/partition list per group, rather than relying on
+// OffsetFetchRequest's "nil Topics means all" behavior, which is only
+// defined for newer request versions. A group with no currently-connected
+// member (e.g. a dead consumer) has no assignment to decode, so this falls
+// back to topics, the cluster's known topic/partition set from
+// scrapeMetadata, and fetches committed offsets for that instead; otherwise
+// an idle group would never publish lag at all, which is exactly the case
+// operators most want alerted on.
+func (c *collector) scrapeGroupLag(topics map[string][]int32, currentOffsets map[string]map[int32]int64) error {
+	kresp, err := c.cl.Client().Request(context.Background(), new(kmsg.ListGroupsRequest))
+	if err != nil {
+		return err
+	}
+	listResp := kresp.(*kmsg.ListGroupsResponse)
+
+	var groups []string
+	for _, group := range listResp.Groups {
+		if c.groupRe != nil && !c.groupRe.MatchString(group.Group) {
+			continue
+		}
+		groups = append(groups, group.Group)
+	}
+
+	c.groupLag.Reset()
+	if len(groups) == 0 {
+		return nil
+	}
+
+	var describeReq kmsg.DescribeGroupsRequest
+	describeReq.Groups = groups
+	kresp, err = c.cl.Client().Request(context.Background(), &describeReq)
+	if err != nil {
+		return err
+	}
+	describeResp := kresp.(*kmsg.DescribeGroupsResponse)
+
+	for _, group := range describeResp.Groups {
+		if err := kerr.ErrorForCode(group.ErrorCode); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to describe group %s: %v\n", group.Group, err)
+			continue
+		}
+
+		// Only the standard consumer embedded protocol's assignment is
+		// understood here; groups running other protocols (e.g. Kafka
+		// Connect, Kafka Streams' own protocol variants) are skipped.
+		if group.ProtocolType != "consumer" {
+			continue
+		}
+
+		assigned := make(map[string][]int32)
+		for _, member := range group.Members {
+			var assignment kmsg.GroupMemberAssignment
+			if err := assignment.ReadFrom(member.MemberAssignment); err != nil {
+				fmt.Fprintf(os.Stderr, "unable to decode assignment for group %s member %s: %v\n", group.Group, member.MemberID, err)
+				continue
+			}
+			for _, topic := range assignment.Topics {
+				if c.topicRe != nil && !c.topicRe.MatchString(topic.Topic) {
+					continue
+				}
+				assigned[topic.Topic] = append(assigned[topic.Topic], topic.Partitions...)
+			}
+		}
+		// No connected member reported an assignment (the group is idle
+		// or its members are still joining); fall back to the cluster's
+		// known topic/partition set so the group's last committed offsets
+		// still get published instead of being skipped entirely.
+		if len(assigned) == 0 {
+			assigned = topics
+		}
+
+		var fetchReq kmsg.OffsetFetchRequest
+		fetchReq.Group = group.Group
+		for topic, partitions := range assigned {
+			fetchReq.Topics = append(fetchReq.Topics, kmsg.OffsetFetchRequestTopic{
+				Topic:      topic,
+				Partitions: partitions,
+			})
+		}
+
+		kresp, err := c.cl.Client().Request(context.Background(), &fetchReq)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to fetch offsets for group %s: %v\n", group.Group, err)
+			continue
+		}
+		fetchResp := kresp.(*kmsg.OffsetFetchResponse)
+
+		for _, topic := range fetchResp.Topics {
+			for _, partition := range topic.Partitions {
+				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+					fmt.Fprintf(os.Stderr, "offset fetch %s %s-%d: %v\n", group.Group, topic.Topic, partition.Partition, err)
+					continue
+				}
+				current, ok := currentOffsets[topic.Topic][partition.Partition]
+				if !ok || partition.Offset < 0 {
+					continue
+				}
+				lag := current - partition.Offset
+				c.groupLag.WithLabelValues(
+					group.Group, topic.Topic, fmt.Sprintf("%d", partition.Partition),
+				).Set(float64(lag))
+			}
+		}
+	}
+	return nil
+}